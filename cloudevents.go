@@ -0,0 +1,60 @@
+package eventwebhook
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	formatJSON              = "json"
+	formatCloudEvents       = "cloudevents"
+	formatCloudEventsBinary = "cloudevents_binary"
+)
+
+// cloudEvent is the CloudEvents 1.0 envelope, per the HTTP structured
+// content mode binding: https://github.com/cloudevents/spec.
+type cloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Time            string      `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+func defaultCloudEventSource() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	return fmt.Sprintf("caddy://%s/events", host)
+}
+
+func newCloudEvent(e caddy.Event, source string) cloudEvent {
+	return cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              uuid.NewString(),
+		Source:          source,
+		Type:            "io.caddyserver.event." + e.Name(),
+		Time:            e.Timestamp().Format(time.RFC3339),
+		DataContentType: "application/json",
+		Data:            e.Data,
+	}
+}
+
+// cloudEventHeaders returns the Ce-* headers used by the CloudEvents HTTP
+// binary content mode, where only the data object is sent as the body.
+func cloudEventHeaders(ce cloudEvent) map[string]string {
+	return map[string]string{
+		"Ce-Id":          ce.ID,
+		"Ce-Source":      ce.Source,
+		"Ce-Type":        ce.Type,
+		"Ce-Time":        ce.Time,
+		"Ce-Specversion": ce.SpecVersion,
+	}
+}
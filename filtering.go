@@ -0,0 +1,129 @@
+package eventwebhook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// shouldHandle reports whether eventName passes the configured Events
+// allow-list and EventsExcept deny-list. Patterns use the caddyevents
+// dotted-name glob syntax (e.g. "tls.cert_obtained", "pki.ca.cert_*").
+func (w *EventWebhook) shouldHandle(eventName string) bool {
+	if len(w.Events) > 0 {
+		allowed := false
+		for _, pattern := range w.Events {
+			if ok, _ := path.Match(pattern, eventName); ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	for _, pattern := range w.EventsExcept {
+		if ok, _ := path.Match(pattern, eventName); ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// redactEventData always returns a copy of data, with the fields named in
+// w.Redact removed and the fields named in w.RedactHash replaced with
+// their SHA-256 hash. Paths are dot-separated JSON paths rooted at the
+// outgoing payload, e.g. "data.identifier"; a leading "data." is stripped
+// since it addresses this same map. The original map is left untouched,
+// even when no redaction is configured, since the caller relies on this
+// to hand out a copy safe for use from another goroutine.
+func (w *EventWebhook) redactEventData(data map[string]interface{}) map[string]interface{} {
+	redacted := deepCopyMap(data)
+	for _, path := range w.Redact {
+		deleteAtPath(redacted, trimDataPrefix(path))
+	}
+	for _, path := range w.RedactHash {
+		hashAtPath(redacted, trimDataPrefix(path))
+	}
+
+	return redacted
+}
+
+func trimDataPrefix(jsonPath string) string {
+	return strings.TrimPrefix(jsonPath, "data.")
+}
+
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return copyMaps(m)
+	}
+	var copied map[string]interface{}
+	if err := json.Unmarshal(encoded, &copied); err != nil {
+		return copyMaps(m)
+	}
+	return copied
+}
+
+// copyMaps copies m and every map[string]interface{} nested in it, so that
+// deleteAtPath and hashAtPath never reach a map shared with the original.
+func copyMaps(m map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = copyMaps(nested)
+		}
+		copied[k] = v
+	}
+	return copied
+}
+
+func walkToParent(m map[string]interface{}, segments []string) (map[string]interface{}, bool) {
+	cur := m
+	for _, segment := range segments {
+		next, ok := cur[segment].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur = next
+	}
+	return cur, true
+}
+
+func deleteAtPath(m map[string]interface{}, jsonPath string) {
+	if jsonPath == "" {
+		return
+	}
+	segments := strings.Split(jsonPath, ".")
+	parent, ok := walkToParent(m, segments[:len(segments)-1])
+	if !ok {
+		return
+	}
+	delete(parent, segments[len(segments)-1])
+}
+
+func hashAtPath(m map[string]interface{}, jsonPath string) {
+	if jsonPath == "" {
+		return
+	}
+	segments := strings.Split(jsonPath, ".")
+	parent, ok := walkToParent(m, segments[:len(segments)-1])
+	if !ok {
+		return
+	}
+	key := segments[len(segments)-1]
+	value, ok := parent[key]
+	if !ok {
+		return
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%v", value)))
+	parent[key] = hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,60 @@
+package eventwebhook
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// webhookMetrics holds the Prometheus collectors shared by all instances of
+// this module. They are registered once, lazily, against the registry of
+// whichever instance is provisioned first. That must be the per-context
+// registry returned by ctx.GetMetricsRegistry(), not the global default
+// registerer: Caddy's admin /metrics endpoint only serves collectors
+// registered on the former.
+var webhookMetrics = struct {
+	init         sync.Once
+	sentTotal    *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	queueDepth   prometheus.Gauge
+	droppedTotal prometheus.Counter
+}{}
+
+func initWebhookMetrics(registry *prometheus.Registry) {
+	const ns, sub = "caddy", "events_webhook"
+
+	webhookMetrics.sentTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "sent_total",
+		Help:      "Count of webhook deliveries, by event name and resulting status.",
+	}, []string{"event", "status"})
+
+	webhookMetrics.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "duration_seconds",
+		Help:      "Duration of webhook delivery HTTP requests.",
+	}, []string{"event"})
+
+	webhookMetrics.queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "queue_depth",
+		Help:      "Current number of events waiting in the delivery queue.",
+	})
+
+	webhookMetrics.droppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: ns,
+		Subsystem: sub,
+		Name:      "dropped_total",
+		Help:      "Count of events dropped because the delivery queue was full.",
+	})
+
+	registry.MustRegister(
+		webhookMetrics.sentTotal,
+		webhookMetrics.duration,
+		webhookMetrics.queueDepth,
+		webhookMetrics.droppedTotal,
+	)
+}
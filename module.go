@@ -3,14 +3,23 @@ package eventwebhook
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/caddyserver/caddy/v2"
 	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
 	"github.com/caddyserver/caddy/v2/modules/caddyevents"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -21,13 +30,75 @@ type EventWebhook struct {
 	Method string `json:"method,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
 	Timeout caddy.Duration `json:"timeout,omitempty"`
+
+	// SigningSecret, when set, is used to sign each webhook delivery with
+	// HMAC-SHA256 so receivers can verify authenticity. Multiple secrets
+	// may be comma-separated to support key rotation; a signature is
+	// emitted for each one.
+	SigningSecret string `json:"signing_secret,omitempty"`
+
+	// Retry configures re-delivery of failed webhooks with exponential
+	// backoff. If nil, failed deliveries are logged and dropped as before.
+	Retry *retryPolicy `json:"retry,omitempty"`
+
+	// Templates routes individual events to their own URL, method,
+	// headers, and body. The first matching template is used; if none
+	// match, the payload selected by Format is sent to w.URL.
+	Templates []*webhookTemplate `json:"templates,omitempty"`
+
+	// Workers is the number of concurrent goroutines delivering webhooks.
+	// QueueSize bounds how many events may be waiting for a free worker.
+	// OnFull decides what happens when the queue is saturated: "block"
+	// (default) applies backpressure to the caller, "drop" discards the
+	// new event, and "newest" discards the oldest queued event to make
+	// room.
+	Workers   int    `json:"workers,omitempty"`
+	QueueSize int    `json:"queue_size,omitempty"`
+	OnFull    string `json:"on_full,omitempty"`
+
+	// Events and EventsExcept restrict which events this handler fires
+	// for, by glob pattern against the caddyevents dotted name. If Events
+	// is set, only matching events are handled; EventsExcept then removes
+	// any that also match its patterns.
+	Events       []string `json:"events,omitempty"`
+	EventsExcept []string `json:"events_except,omitempty"`
+
+	// Redact removes the named JSON paths from the event data before
+	// delivery; RedactHash replaces them with a SHA-256 hash instead of
+	// removing them outright.
+	Redact     []string `json:"redact,omitempty"`
+	RedactHash []string `json:"redact_hash,omitempty"`
+
+	// Format selects the output payload shape: "json" (default) sends the
+	// handler's normal JSON payload, "cloudevents" sends the event data in
+	// a CloudEvents 1.0 structured-mode envelope, and "cloudevents_binary"
+	// sends just the event data with the envelope fields as Ce-* headers.
+	// Source sets the CloudEvents "source" attribute.
+	Format string `json:"format,omitempty"`
+	Source string `json:"source,omitempty"`
+
+	// TLS configures the transport's TLS behavior for private CAs and
+	// mutual TLS. MaxIdleConns and IdleConnTimeout tune the persistent
+	// connection pool shared by every delivery from this handler.
+	TLS             *transportConfig `json:"tls,omitempty"`
+	MaxIdleConns    int              `json:"max_idle_conns,omitempty"`
+	IdleConnTimeout caddy.Duration   `json:"idle_conn_timeout,omitempty"`
+
+	signingSecrets []string
+	envVars        map[string]string
+	transport      *http.Transport
+
+	jobQueue   chan caddy.Event
+	retryQueue chan retryJob
+	shutdown   chan struct{}
+	wg         sync.WaitGroup
 }
 
 func init() {
-	caddy.RegisterModule(EventWebhook{})
+	caddy.RegisterModule(new(EventWebhook))
 }
 
-func (EventWebhook) CaddyModule() caddy.ModuleInfo {
+func (*EventWebhook) CaddyModule() caddy.ModuleInfo {
 	return caddy.ModuleInfo{
 		ID:  "events.handlers.webhook",
 		New: func() caddy.Module { return new(EventWebhook) },
@@ -36,6 +107,7 @@ func (EventWebhook) CaddyModule() caddy.ModuleInfo {
 
 func (w *EventWebhook) Provision(ctx caddy.Context) error {
 	w.Logger = ctx.Logger(w)
+	webhookMetrics.init.Do(func() { initWebhookMetrics(ctx.GetMetricsRegistry()) })
 
 	if w.Method == "" {
 		w.Method = "POST"
@@ -46,87 +118,300 @@ func (w *EventWebhook) Provision(ctx caddy.Context) error {
 	if w.Headers == nil {
 		w.Headers = make(map[string]string)
 	}
+	if w.Workers <= 0 {
+		w.Workers = 4
+	}
+	if w.QueueSize <= 0 {
+		w.QueueSize = 100
+	}
+	if w.OnFull == "" {
+		w.OnFull = onFullBlock
+	}
+	if w.Format == "" {
+		w.Format = formatJSON
+	}
+	if w.Source == "" {
+		w.Source = defaultCloudEventSource()
+	}
+	if w.MaxIdleConns <= 0 {
+		w.MaxIdleConns = 100
+	}
+	if w.IdleConnTimeout == 0 {
+		w.IdleConnTimeout = caddy.Duration(90 * time.Second)
+	}
 
-	w.Logger.Info("module loaded");
-	
+	w.transport = &http.Transport{
+		MaxIdleConns:    w.MaxIdleConns,
+		IdleConnTimeout: time.Duration(w.IdleConnTimeout),
+	}
+	if w.TLS != nil {
+		tlsConfig, err := w.TLS.build()
+		if err != nil {
+			return fmt.Errorf("building tls config: %w", err)
+		}
+		w.transport.TLSClientConfig = tlsConfig
+	}
+	if w.SigningSecret != "" {
+		for _, secret := range strings.Split(w.SigningSecret, ",") {
+			secret = strings.TrimSpace(secret)
+			if secret != "" {
+				w.signingSecrets = append(w.signingSecrets, secret)
+			}
+		}
+	}
+	if w.Retry != nil {
+		w.Retry.setDefaults()
+		w.retryQueue = make(chan retryJob, 1000)
+	}
+	if len(w.Templates) > 0 {
+		w.envVars = buildEnvMap()
+		for _, t := range w.Templates {
+			if err := t.provision(w.Method); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.shutdown = make(chan struct{})
+	w.jobQueue = make(chan caddy.Event, w.QueueSize)
+	for i := 0; i < w.Workers; i++ {
+		w.wg.Add(1)
+		go w.poolWorker()
+	}
+	if w.Retry != nil {
+		w.wg.Add(1)
+		go w.retryWorker()
+	}
+
+	if ce := w.Logger.Check(zap.InfoLevel, "module loaded"); ce != nil {
+		ce.Write(zap.Int("workers", w.Workers), zap.Int("queue_size", w.QueueSize))
+	}
+
+	return nil
+}
+
+// Cleanup stops the pool workers and the retry worker (if any) started in
+// Provision, and closes the transport's idle connections.
+func (w *EventWebhook) Cleanup() error {
+	if w.shutdown != nil {
+		close(w.shutdown)
+		w.wg.Wait()
+	}
+	if w.transport != nil {
+		w.transport.CloseIdleConnections()
+	}
 	return nil
 }
 
 // Caddy Event Handle
 func (w *EventWebhook) Handle(ctx context.Context, e caddy.Event) error {
-	w.Logger.Debug("handling event",
-		zap.String("event_name", e.Name()),
-		zap.String("webhook_url", w.URL))
+	if !w.shouldHandle(e.Name()) {
+		if ce := w.Logger.Check(zap.DebugLevel, "skipping filtered event"); ce != nil {
+			ce.Write(zap.String("event_name", e.Name()))
+		}
+		return nil
+	}
+
+	if ce := w.Logger.Check(zap.DebugLevel, "handling event"); ce != nil {
+		ce.Write(zap.String("event_name", e.Name()), zap.String("webhook_url", w.URL))
+	}
+
+	// e.Data is not synchronized, so it must not be read from the worker
+	// goroutines it's about to be handed to; redactEventData always
+	// returns a fresh copy, which also doubles as that snapshot. Doing
+	// this once here, rather than per delivery attempt, keeps redact_hash
+	// output stable across retries instead of re-hashing already-hashed
+	// fields.
+	e.Data = w.redactEventData(e.Data)
+
+	w.enqueue(e)
 
-	go w.sendWebhook(e)
-	
 	return nil
 }
 
 // HTTP Request
-func (w *EventWebhook) sendWebhook(e caddy.Event) {
+func (w *EventWebhook) sendWebhook(e caddy.Event, attempt int) {
 	var eventName = e.Name()
 	var requestBody []byte
 	var err error
-	
-	payload := map[string]interface{}{
-		"event": eventName,
-		"eventTimestamp": e.Timestamp().Format(time.RFC3339),
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
-	}
-	if e.Data != nil {
-		payload["data"] = e.Data
-	}
-	
-	requestBody, err = json.Marshal(payload)
-	if err != nil {
-		w.Logger.Error("failed to marshal webhook payload", 
-			zap.String("event", eventName),
-			zap.Error(err))
-		return
+
+	url := w.URL
+	method := w.Method
+	headers := w.Headers
+	var formatHeaders map[string]string
+
+	tmpl := w.matchTemplate(eventName)
+	if tmpl != nil {
+		url = tmpl.URL
+		method = tmpl.Method
+		if tmpl.Headers != nil {
+			headers = tmpl.Headers
+		}
+
+		requestBody, err = tmpl.render(e, w.envVars)
+		if err != nil {
+			if ce := w.Logger.Check(zap.ErrorLevel, "failed to render webhook template"); ce != nil {
+				ce.Write(zap.String("event", eventName), zap.Error(err))
+			}
+			return
+		}
+	} else {
+		switch w.Format {
+		case formatCloudEvents:
+			cloudEv := newCloudEvent(e, w.Source)
+			requestBody, err = json.Marshal(cloudEv)
+			formatHeaders = map[string]string{"Content-Type": "application/cloudevents+json"}
+
+		case formatCloudEventsBinary:
+			cloudEv := newCloudEvent(e, w.Source)
+			requestBody, err = json.Marshal(e.Data)
+			formatHeaders = cloudEventHeaders(cloudEv)
+
+		default:
+			payload := map[string]interface{}{
+				"event": eventName,
+				"eventTimestamp": e.Timestamp().Format(time.RFC3339),
+				"timestamp": time.Now().UTC().Format(time.RFC3339),
+			}
+			if e.Data != nil {
+				payload["data"] = e.Data
+			}
+			requestBody, err = json.Marshal(payload)
+		}
+		if err != nil {
+			if ce := w.Logger.Check(zap.ErrorLevel, "failed to marshal webhook payload"); ce != nil {
+				ce.Write(zap.String("event", eventName), zap.Error(err))
+			}
+			return
+		}
 	}
-	
+
 	client := &http.Client{
-		Timeout: time.Duration(w.Timeout),
+		Timeout:   time.Duration(w.Timeout),
+		Transport: w.transport,
 	}
-	
-	req, err := http.NewRequest(w.Method, w.URL, bytes.NewBuffer(requestBody))
+
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(requestBody))
 	if err != nil {
-		w.Logger.Error("failed to create webhook request",
-			zap.String("event", eventName),
-			zap.Error(err))
+		if ce := w.Logger.Check(zap.ErrorLevel, "failed to create webhook request"); ce != nil {
+			ce.Write(zap.String("event", eventName), zap.Error(err))
+		}
 		return
 	}
 
 	req.Header.Set("User-Agent", "Caddy Event Webhook")
-	for key, value := range w.Headers {
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range formatHeaders {
 		req.Header.Set(key, value)
 	}
-	req.Header.Set("Content-Type", "application/json")
-	
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	if len(w.signingSecrets) > 0 {
+		timestamp := time.Now().Unix()
+		req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+		req.Header.Set("X-Webhook-Id", uuid.NewString())
+		req.Header.Set("X-Webhook-Signature", signWebhookBody(w.signingSecrets, timestamp, requestBody))
+	}
+
+	start := time.Now()
 	resp, err := client.Do(req)
+	webhookMetrics.duration.WithLabelValues(eventName).Observe(time.Since(start).Seconds())
 	if err != nil {
-		w.Logger.Error("failed to send webhook",
-			zap.String("event", eventName),
-			zap.String("url", w.URL),
-			zap.Error(err))
+		webhookMetrics.sentTotal.WithLabelValues(eventName, "error").Inc()
+		if ce := w.Logger.Check(zap.ErrorLevel, "failed to send webhook"); ce != nil {
+			ce.Write(zap.String("event", eventName), zap.String("url", url), zap.Error(err))
+		}
+		w.handleDeliveryFailure(e, attempt, 0, nil, err)
 		return
 	}
 	defer resp.Body.Close()
-	
+
+	webhookMetrics.sentTotal.WithLabelValues(eventName, strconv.Itoa(resp.StatusCode)).Inc()
+
 	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		w.Logger.Debug("webhook sent successfully",
-			zap.String("event", eventName),
-			zap.Int("status", resp.StatusCode),
-			zap.String("url", w.URL))
+		if ce := w.Logger.Check(zap.DebugLevel, "webhook sent successfully"); ce != nil {
+			ce.Write(zap.String("event", eventName), zap.Int("status", resp.StatusCode), zap.String("url", url))
+		}
 	} else {
-		w.Logger.Warn("webhook returned non-2xx status",
-			zap.String("event", eventName),
-			zap.Int("status", resp.StatusCode),
-			zap.String("url", w.URL),
-			zap.String("response", string(body)))
+		if ce := w.Logger.Check(zap.WarnLevel, "webhook returned non-2xx status"); ce != nil {
+			ce.Write(
+				zap.String("event", eventName),
+				zap.Int("status", resp.StatusCode),
+				zap.String("url", url),
+				zap.String("response", string(body)))
+		}
+		w.handleDeliveryFailure(e, attempt, resp.StatusCode, resp.Header, nil)
+	}
+}
+
+// signWebhookBody computes an HMAC-SHA256 signature of "<timestamp>.<body>"
+// for each secret and returns them combined as a single header value in the
+// form "t=<ts>,v1=<hex>,v1=<hex>,...", one v1 entry per secret so receivers
+// can verify against any active key during rotation.
+func signWebhookBody(secrets []string, timestamp int64, body []byte) string {
+	signedPayload := fmt.Sprintf("%d.%s", timestamp, body)
+
+	header := fmt.Sprintf("t=%d", timestamp)
+	for _, secret := range secrets {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(signedPayload))
+		header += ",v1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	return header
+}
+
+// VerifyWebhookSignature validates an X-Webhook-Signature header against the
+// given payload and secret, rejecting signatures whose timestamp is older
+// than maxAge to prevent replay attacks. It accepts any one of the secrets
+// used by the sender during key rotation.
+func VerifyWebhookSignature(payload []byte, header, secret string, maxAge time.Duration) error {
+	var timestamp int64
+	var signatures []string
+
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid signature timestamp: %w", err)
+			}
+			timestamp = ts
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+
+	if timestamp == 0 {
+		return errors.New("signature header is missing timestamp")
+	}
+	if len(signatures) == 0 {
+		return errors.New("signature header is missing v1 signature")
+	}
+	if maxAge > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > maxAge {
+			return fmt.Errorf("signature timestamp is too old: %s", age)
+		}
+	}
+
+	signedPayload := fmt.Sprintf("%d.%s", timestamp, payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signedPayload))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
 	}
+	return errors.New("no matching signature found")
 }
 
 func (w *EventWebhook) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
@@ -161,6 +446,127 @@ func (w *EventWebhook) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 				return d.Errf("invalid timeout duration: %v", err)
 			}
 			w.Timeout = caddy.Duration(dur)
+
+		case "signing_secret":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			w.SigningSecret = d.Val()
+
+		case "retry":
+			policy := new(retryPolicy)
+			if err := policy.UnmarshalCaddyfile(d); err != nil {
+				return err
+			}
+			w.Retry = policy
+
+		case "template":
+			tmpl := new(webhookTemplate)
+			if err := tmpl.UnmarshalCaddyfile(d); err != nil {
+				return err
+			}
+			w.Templates = append(w.Templates, tmpl)
+
+		case "workers":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			n, err := strconv.Atoi(d.Val())
+			if err != nil {
+				return d.Errf("invalid workers: %v", err)
+			}
+			w.Workers = n
+
+		case "queue_size":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			n, err := strconv.Atoi(d.Val())
+			if err != nil {
+				return d.Errf("invalid queue_size: %v", err)
+			}
+			w.QueueSize = n
+
+		case "on_full":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			switch d.Val() {
+			case onFullBlock, onFullDrop, onFullNewest:
+				w.OnFull = d.Val()
+			default:
+				return d.Errf("invalid on_full value: %s", d.Val())
+			}
+
+		case "events":
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return d.ArgErr()
+			}
+			w.Events = append(w.Events, args...)
+
+		case "events_except":
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return d.ArgErr()
+			}
+			w.EventsExcept = append(w.EventsExcept, args...)
+
+		case "redact":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			w.Redact = append(w.Redact, d.Val())
+
+		case "redact_hash":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			w.RedactHash = append(w.RedactHash, d.Val())
+
+		case "format":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			switch d.Val() {
+			case formatJSON, formatCloudEvents, formatCloudEventsBinary:
+				w.Format = d.Val()
+			default:
+				return d.Errf("invalid format value: %s", d.Val())
+			}
+
+		case "source":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			w.Source = d.Val()
+
+		case "tls":
+			tlsConfig := new(transportConfig)
+			if err := tlsConfig.UnmarshalCaddyfile(d); err != nil {
+				return err
+			}
+			w.TLS = tlsConfig
+
+		case "max_idle_conns":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			n, err := strconv.Atoi(d.Val())
+			if err != nil {
+				return d.Errf("invalid max_idle_conns: %v", err)
+			}
+			w.MaxIdleConns = n
+
+		case "idle_conn_timeout":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			dur, err := time.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid idle_conn_timeout: %v", err)
+			}
+			w.IdleConnTimeout = caddy.Duration(dur)
 		default:
 			return d.Errf("unrecognized subdirective: %s", d.Val())
 		}
@@ -176,6 +582,7 @@ func (w *EventWebhook) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
 var (
 	_ caddy.Module          = (*EventWebhook)(nil)
 	_ caddy.Provisioner     = (*EventWebhook)(nil)
+	_ caddy.CleanerUpper    = (*EventWebhook)(nil)
 	_ caddyevents.Handler   = (*EventWebhook)(nil)
 	_ caddyfile.Unmarshaler = (*EventWebhook)(nil)
 )
\ No newline at end of file
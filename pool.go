@@ -0,0 +1,63 @@
+package eventwebhook
+
+import (
+	"github.com/caddyserver/caddy/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	onFullBlock  = "block"
+	onFullDrop   = "drop"
+	onFullNewest = "newest"
+)
+
+// enqueue submits an event to the bounded worker pool for delivery,
+// applying the configured on_full policy when the queue is saturated.
+func (w *EventWebhook) enqueue(e caddy.Event) {
+	switch w.OnFull {
+	case onFullDrop:
+		select {
+		case w.jobQueue <- e:
+		default:
+			webhookMetrics.droppedTotal.Inc()
+			if ce := w.Logger.Check(zap.WarnLevel, "webhook queue full, dropping event"); ce != nil {
+				ce.Write(zap.String("event", e.Name()))
+			}
+		}
+
+	case onFullNewest:
+		select {
+		case w.jobQueue <- e:
+		default:
+			select {
+			case <-w.jobQueue:
+				webhookMetrics.droppedTotal.Inc()
+			default:
+			}
+			select {
+			case w.jobQueue <- e:
+			default:
+			}
+		}
+
+	default: // onFullBlock
+		w.jobQueue <- e
+	}
+
+	webhookMetrics.queueDepth.Set(float64(len(w.jobQueue)))
+}
+
+// poolWorker pulls events off the queue and delivers them. There are
+// w.Workers of these running per handler instance.
+func (w *EventWebhook) poolWorker() {
+	defer w.wg.Done()
+	for {
+		select {
+		case e := <-w.jobQueue:
+			webhookMetrics.queueDepth.Set(float64(len(w.jobQueue)))
+			w.sendWebhook(e, 1)
+		case <-w.shutdown:
+			return
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package eventwebhook
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	"go.uber.org/zap"
+)
+
+// retryPolicy configures re-delivery of failed webhooks with exponential
+// backoff. Deliveries are retried until max_attempts is reached, at which
+// point the event is dead-lettered to the log.
+type retryPolicy struct {
+	MaxAttempts    int            `json:"max_attempts,omitempty"`
+	InitialBackoff caddy.Duration `json:"initial_backoff,omitempty"`
+	MaxBackoff     caddy.Duration `json:"max_backoff,omitempty"`
+	Jitter         float64        `json:"jitter,omitempty"`
+	RetryOn        []string       `json:"retry_on,omitempty"`
+}
+
+func (p *retryPolicy) setDefaults() {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 5
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = caddy.Duration(time.Second)
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = caddy.Duration(60 * time.Second)
+	}
+	if p.Jitter == 0 {
+		p.Jitter = 0.2
+	}
+	if p.RetryOn == nil {
+		p.RetryOn = []string{"5xx", "429", "network"}
+	}
+}
+
+// UnmarshalCaddyfile parses a `retry { ... }` block, e.g.:
+//
+//	retry {
+//		max_attempts 5
+//		initial_backoff 1s
+//		max_backoff 60s
+//		jitter 0.2
+//		retry_on 5xx,429,network
+//	}
+func (p *retryPolicy) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.NextBlock(1) {
+		switch d.Val() {
+		case "max_attempts":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			n, err := strconv.Atoi(d.Val())
+			if err != nil {
+				return d.Errf("invalid max_attempts: %v", err)
+			}
+			p.MaxAttempts = n
+
+		case "initial_backoff":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			dur, err := time.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid initial_backoff: %v", err)
+			}
+			p.InitialBackoff = caddy.Duration(dur)
+
+		case "max_backoff":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			dur, err := time.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid max_backoff: %v", err)
+			}
+			p.MaxBackoff = caddy.Duration(dur)
+
+		case "jitter":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			f, err := strconv.ParseFloat(d.Val(), 64)
+			if err != nil {
+				return d.Errf("invalid jitter: %v", err)
+			}
+			p.Jitter = f
+
+		case "retry_on":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			p.RetryOn = strings.Split(d.Val(), ",")
+
+		default:
+			return d.Errf("unrecognized retry subdirective: %s", d.Val())
+		}
+	}
+	return nil
+}
+
+// retryJob is a queued re-delivery attempt for an event that previously
+// failed.
+type retryJob struct {
+	event   caddy.Event
+	attempt int
+}
+
+// retryWorker drains the retry queue and re-attempts delivery for each job
+// as it becomes due. There is one worker per handler instance.
+func (w *EventWebhook) retryWorker() {
+	defer w.wg.Done()
+	for {
+		select {
+		case job := <-w.retryQueue:
+			w.sendWebhook(job.event, job.attempt)
+		case <-w.shutdown:
+			return
+		}
+	}
+}
+
+// handleDeliveryFailure decides whether a failed delivery should be
+// retried, dead-lettered, or simply dropped (when no retry policy is
+// configured, preserving the original fire-and-forget behavior, or when
+// the failure matches none of the retry_on classes).
+func (w *EventWebhook) handleDeliveryFailure(e caddy.Event, attempt int, status int, headers http.Header, sendErr error) {
+	if w.Retry == nil {
+		return
+	}
+
+	if !isRetryable(status, sendErr, w.Retry.RetryOn) {
+		return
+	}
+
+	if attempt >= w.Retry.MaxAttempts {
+		w.deadLetter(e, attempt, status, sendErr)
+		return
+	}
+
+	backoff := computeBackoff(*w.Retry, attempt)
+	if retryAfter, ok := parseRetryAfter(headers); ok && retryAfter > backoff {
+		backoff = retryAfter
+	}
+	nextAttempt := attempt + 1
+
+	if ce := w.Logger.Check(zap.WarnLevel, "scheduling webhook retry"); ce != nil {
+		ce.Write(
+			zap.String("event", e.Name()),
+			zap.Int("attempt", attempt),
+			zap.Int("next_attempt", nextAttempt),
+			zap.Time("next_attempt_at", time.Now().Add(backoff)),
+			zap.NamedError("last_error", sendErr),
+			zap.Int("last_status", status))
+	}
+
+	time.AfterFunc(backoff, func() {
+		select {
+		case w.retryQueue <- retryJob{event: e, attempt: nextAttempt}:
+		case <-w.shutdown:
+		}
+	})
+}
+
+// deadLetter logs the fully serialized event payload once retries are
+// exhausted so operators can pipe it to another sink.
+func (w *EventWebhook) deadLetter(e caddy.Event, attempt int, status int, sendErr error) {
+	if ce := w.Logger.Check(zap.WarnLevel, "dead_letter"); ce != nil {
+		ce.Write(
+			zap.String("event", e.Name()),
+			zap.Int("attempts", attempt),
+			zap.Int("last_status", status),
+			zap.NamedError("last_error", sendErr),
+			zap.Any("payload", e.Data))
+	}
+}
+
+// isRetryable reports whether a failed delivery matches one of the
+// configured retry_on classes: "network" for transport errors, "5xx" for
+// any 5xx status, or an exact status code such as "429".
+func isRetryable(status int, sendErr error, retryOn []string) bool {
+	for _, class := range retryOn {
+		switch class {
+		case "network":
+			if sendErr != nil {
+				return true
+			}
+		case "5xx":
+			if status >= 500 && status <= 599 {
+				return true
+			}
+		default:
+			if code, err := strconv.Atoi(class); err == nil && code == status {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// computeBackoff returns the exponential backoff duration for the given
+// attempt number, capped at MaxBackoff and randomized by +/- Jitter.
+func computeBackoff(policy retryPolicy, attempt int) time.Duration {
+	backoff := float64(policy.InitialBackoff) * math.Pow(2, float64(attempt-1))
+	if max := float64(policy.MaxBackoff); backoff > max {
+		backoff = max
+	}
+	if policy.Jitter > 0 {
+		backoff *= 1 + policy.Jitter*(2*rand.Float64()-1)
+	}
+	if backoff < 0 {
+		backoff = 0
+	}
+	return time.Duration(backoff)
+}
+
+// parseRetryAfter reads the Retry-After header (seconds or HTTP-date form)
+// from a failed response, if present. It does not look at the status code,
+// so the header is honored on any retryable status, not only 429/503.
+func parseRetryAfter(headers http.Header) (time.Duration, bool) {
+	if headers == nil {
+		return 0, false
+	}
+	value := headers.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when), true
+	}
+	return 0, false
+}
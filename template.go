@@ -0,0 +1,174 @@
+package eventwebhook
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// webhookTemplate routes a subset of events to their own URL, method,
+// headers, and templated body, so a single handler can dispatch to
+// services (Slack, Discord, a generic REST API) that each expect their own
+// payload shape.
+type webhookTemplate struct {
+	Events  []string          `json:"events,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+
+	bodyTemplate *template.Template
+}
+
+// templateData is made available to a template's body.
+type templateData struct {
+	Event templateEventData
+	Env   map[string]string
+	Now   time.Time
+}
+
+type templateEventData struct {
+	Name      string
+	Timestamp time.Time
+	Data      map[string]interface{}
+}
+
+func (t *webhookTemplate) provision(defaultMethod string) error {
+	if t.Method == "" {
+		t.Method = defaultMethod
+	}
+
+	tmpl, err := template.New("body").Parse(t.Body)
+	if err != nil {
+		return fmt.Errorf("parsing template body: %w", err)
+	}
+	t.bodyTemplate = tmpl
+
+	return nil
+}
+
+// matches reports whether eventName matches one of this template's event
+// glob patterns, e.g. "tls.cert_obtained" or "pki.ca.cert_*".
+func (t *webhookTemplate) matches(eventName string) bool {
+	for _, pattern := range t.Events {
+		if ok, _ := path.Match(pattern, eventName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *webhookTemplate) render(e caddy.Event, env map[string]string) ([]byte, error) {
+	data := templateData{
+		Event: templateEventData{
+			Name:      e.Name(),
+			Timestamp: e.Timestamp(),
+			Data:      e.Data,
+		},
+		Env: env,
+		Now: time.Now(),
+	}
+
+	var buf bytes.Buffer
+	if err := t.bodyTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalCaddyfile parses a `template { ... }` block, e.g.:
+//
+//	template {
+//		events tls.cert_obtained tls.cert_failed
+//		url https://hooks.slack.com/services/...
+//		method POST
+//		header Content-Type application/json
+//		body `{"text": "event {{.Event.Name}} at {{.Now}}"}`
+//	}
+func (t *webhookTemplate) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.NextBlock(1) {
+		switch d.Val() {
+		case "events":
+			args := d.RemainingArgs()
+			if len(args) == 0 {
+				return d.ArgErr()
+			}
+			t.Events = append(t.Events, args...)
+
+		case "url":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			t.URL = d.Val()
+
+		case "method":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			t.Method = d.Val()
+
+		case "header":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			key := d.Val()
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			if t.Headers == nil {
+				t.Headers = make(map[string]string)
+			}
+			t.Headers[key] = d.Val()
+
+		case "body":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			t.Body = d.Val()
+
+		default:
+			return d.Errf("unrecognized template subdirective: %s", d.Val())
+		}
+	}
+
+	if len(t.Events) == 0 {
+		return d.Err("template requires at least one events pattern")
+	}
+	if t.URL == "" {
+		return d.Err("template requires a url")
+	}
+
+	return nil
+}
+
+// matchTemplate returns the first configured template whose events patterns
+// match eventName, or nil if none match (callers fall back to the payload
+// selected by the handler's Format).
+func (w *EventWebhook) matchTemplate(eventName string) *webhookTemplate {
+	for _, t := range w.Templates {
+		if t.matches(eventName) {
+			return t
+		}
+	}
+	return nil
+}
+
+// buildEnvMap snapshots the process environment once at Provision time for
+// use by template bodies' .Env field.
+func buildEnvMap() map[string]string {
+	environ := os.Environ()
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i >= 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+	return env
+}
@@ -0,0 +1,123 @@
+package eventwebhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+)
+
+// transportConfig configures the TLS behavior of the persistent transport
+// used to deliver webhooks, for on-prem receivers behind private CAs or
+// that require mutual TLS.
+type transportConfig struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	ClientCert         string `json:"client_cert,omitempty"`
+	ClientKey          string `json:"client_key,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	MinVersion         string `json:"min_version,omitempty"`
+}
+
+// build turns the configuration into a *tls.Config, reading and parsing
+// any referenced CA and certificate files.
+func (t *transportConfig) build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		caCert, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in ca_file %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if t.ClientCert != "" || t.ClientKey != "" {
+		if t.ClientCert == "" || t.ClientKey == "" {
+			return nil, fmt.Errorf("tls: both client_cert and client_key are required")
+		}
+		cert, err := tls.LoadX509KeyPair(t.ClientCert, t.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	switch t.MinVersion {
+	case "", "tls1.2":
+		cfg.MinVersion = tls.VersionTLS12
+	case "tls1.3":
+		cfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("invalid min_version: %s", t.MinVersion)
+	}
+
+	return cfg, nil
+}
+
+// UnmarshalCaddyfile parses a `tls { ... }` block, e.g.:
+//
+//	tls {
+//		ca_file /etc/caddy/ca.pem
+//		client_cert /etc/caddy/client.pem
+//		client_key /etc/caddy/client.key
+//		server_name webhooks.internal
+//		insecure_skip_verify
+//		min_version tls1.2
+//	}
+func (t *transportConfig) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	for d.NextBlock(1) {
+		switch d.Val() {
+		case "ca_file":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			t.CAFile = d.Val()
+
+		case "client_cert":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			t.ClientCert = d.Val()
+
+		case "client_key":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			t.ClientKey = d.Val()
+
+		case "server_name":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			t.ServerName = d.Val()
+
+		case "insecure_skip_verify":
+			t.InsecureSkipVerify = true
+
+		case "min_version":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			switch d.Val() {
+			case "tls1.2", "tls1.3":
+				t.MinVersion = d.Val()
+			default:
+				return d.Errf("invalid min_version: %s", d.Val())
+			}
+
+		default:
+			return d.Errf("unrecognized tls subdirective: %s", d.Val())
+		}
+	}
+	return nil
+}